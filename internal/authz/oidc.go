@@ -0,0 +1,45 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package authz authenticates callers of the Cloud Scheduler / Pub/Sub
+// triggered ingestion routes so they can't be scraped anonymously.
+package authz
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/api/idtoken"
+)
+
+// RequireGoogleOIDC returns a Gin middleware that verifies the caller
+// presented a Google-signed OIDC ID token in the Authorization header with
+// the given audience, as Cloud Scheduler and Pub/Sub push subscriptions do
+// when configured with an OIDC token.
+func RequireGoogleOIDC(audience string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
+			return
+		}
+		if _, err := idtoken.Validate(c.Request.Context(), token, audience); err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid id token"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package forecast blends a branch's expected attendance trendline with its
+// recent actual occupancy samples to predict near-future occupancy, using
+// exponential smoothing of the residual between actual and expected.
+package forecast
+
+import "math"
+
+// DefaultAlpha and DefaultDecay are the smoothing and decay factors used
+// when Options leaves them unset.
+const (
+	DefaultAlpha = 0.4
+	DefaultDecay = 0.7
+)
+
+// residualFloor is the residual stddev, in the same units as Percentage,
+// above which confidence bottoms out at zero.
+const residualFloor = 0.25
+
+// ExpectedPoint is one hour of a branch's stored expected attendance curve.
+type ExpectedPoint struct {
+	Hour       int
+	Percentage float64
+}
+
+// ActualSample is one stored occupancy sample for a branch, already
+// resolved to an hour-of-day.
+type ActualSample struct {
+	Hour       int
+	Percentage float64
+}
+
+// Point is a single predicted hour, ready to serialize as part of a
+// /forecast response.
+type Point struct {
+	Hour       int     `json:"hour"`
+	Expected   float64 `json:"expected"`
+	Predicted  float64 `json:"predicted"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Options configures the smoothing. Zero values fall back to the package
+// defaults.
+type Options struct {
+	Alpha float64
+	Decay float64
+}
+
+func (o Options) withDefaults() Options {
+	if o.Alpha == 0 {
+		o.Alpha = DefaultAlpha
+	}
+	if o.Decay == 0 {
+		o.Decay = DefaultDecay
+	}
+	return o
+}
+
+// Generate predicts the `horizon` hours following the last actual sample,
+// by smoothing the residual between actual and expected samples
+// (r_t = alpha*(actual_t - expected_t) + (1-alpha)*r_t-1) and decaying it
+// forward: y_t+k = clamp(expected_t+k + r_t*decay^k, 0, 1).
+//
+// actual must be sorted by Hour ascending and expected indexed by the same
+// hour-of-day space; if actual is empty, the forecast is just the expected
+// curve with zero confidence.
+func Generate(expected []ExpectedPoint, actual []ActualSample, horizon int, opts Options) []Point {
+	opts = opts.withDefaults()
+	expectedByHour := make(map[int]float64, len(expected))
+	for _, e := range expected {
+		expectedByHour[e.Hour] = e.Percentage
+	}
+
+	var residual float64
+	var residuals []float64
+	lastHour := 0
+	for _, a := range actual {
+		r := a.Percentage - expectedByHour[a.Hour]
+		residual = opts.Alpha*r + (1-opts.Alpha)*residual
+		residuals = append(residuals, r)
+		lastHour = a.Hour
+	}
+	var confidence float64
+	if len(residuals) > 0 {
+		confidence = 1 - math.Min(1, stddev(residuals)/residualFloor)
+	}
+
+	points := make([]Point, 0, horizon)
+	for k := 1; k <= horizon; k++ {
+		hour := lastHour + k
+		exp := expectedByHour[hour]
+		predicted := clamp(exp+residual*math.Pow(opts.Decay, float64(k)), 0, 1)
+		points = append(points, Point{Hour: hour, Expected: exp, Predicted: predicted, Confidence: confidence})
+	}
+	return points
+}
+
+func stddev(xs []float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	var mean float64
+	for _, x := range xs {
+		mean += x
+	}
+	mean /= float64(len(xs))
+
+	var sumSq float64
+	for _, x := range xs {
+		d := x - mean
+		sumSq += d * d
+	}
+	return math.Sqrt(sumSq / float64(len(xs)))
+}
+
+func clamp(v, lo, hi float64) float64 {
+	switch {
+	case v < lo:
+		return lo
+	case v > hi:
+		return hi
+	default:
+		return v
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forecast
+
+import (
+	"math"
+	"testing"
+)
+
+const epsilon = 1e-9
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < epsilon
+}
+
+func TestGenerateDeterministicResidualSequence(t *testing.T) {
+	expected := []ExpectedPoint{
+		{Hour: 9, Percentage: 0.2},
+		{Hour: 10, Percentage: 0.3},
+		{Hour: 11, Percentage: 0.4},
+		{Hour: 12, Percentage: 0.5},
+		{Hour: 13, Percentage: 0.5},
+	}
+	// Actual consistently runs 0.1 above expected, so the residual
+	// converges toward 0.1 and the stddev of the per-hour residuals is 0.
+	actual := []ActualSample{
+		{Hour: 9, Percentage: 0.3},
+		{Hour: 10, Percentage: 0.4},
+		{Hour: 11, Percentage: 0.5},
+	}
+
+	points := Generate(expected, actual, 2, Options{Alpha: 0.4, Decay: 0.7})
+	if len(points) != 2 {
+		t.Fatalf("expected 2 points, got %d", len(points))
+	}
+
+	// r_9 = 0.4*0.1 = 0.04
+	// r_10 = 0.4*0.1 + 0.6*0.04 = 0.064
+	// r_11 = 0.4*0.1 + 0.6*0.064 = 0.0784
+	wantResidual := 0.0784
+	wantConfidence := 1.0 // residuals are all exactly 0.1, stddev 0
+
+	wantHour12 := clamp(0.5+wantResidual*math.Pow(0.7, 1), 0, 1)
+	wantHour13 := clamp(0.5+wantResidual*math.Pow(0.7, 2), 0, 1)
+
+	if points[0].Hour != 12 || !almostEqual(points[0].Predicted, wantHour12) {
+		t.Errorf("point 0 = %+v, want hour 12 predicted %v", points[0], wantHour12)
+	}
+	if points[1].Hour != 13 || !almostEqual(points[1].Predicted, wantHour13) {
+		t.Errorf("point 1 = %+v, want hour 13 predicted %v", points[1], wantHour13)
+	}
+	if !almostEqual(points[0].Confidence, wantConfidence) {
+		t.Errorf("confidence = %v, want %v", points[0].Confidence, wantConfidence)
+	}
+}
+
+func TestGenerateClampsPredictedToUnitRange(t *testing.T) {
+	expected := []ExpectedPoint{
+		{Hour: 1, Percentage: 0.95},
+		{Hour: 2, Percentage: 0.99},
+	}
+	actual := []ActualSample{{Hour: 1, Percentage: 1.0}}
+
+	points := Generate(expected, actual, 1, Options{Alpha: 1, Decay: 1})
+	if len(points) != 1 {
+		t.Fatalf("expected 1 point, got %d", len(points))
+	}
+	if points[0].Predicted != 1 {
+		t.Errorf("Predicted = %v, want clamped to 1", points[0].Predicted)
+	}
+}
+
+func TestGenerateWithNoActualSamplesHasZeroConfidence(t *testing.T) {
+	expected := []ExpectedPoint{{Hour: 5, Percentage: 0.3}}
+
+	points := Generate(expected, nil, 1, Options{})
+	if len(points) != 1 {
+		t.Fatalf("expected 1 point, got %d", len(points))
+	}
+	if points[0].Hour != 1 {
+		t.Errorf("Hour = %d, want 1 (no actual samples means lastHour defaults to 0)", points[0].Hour)
+	}
+	if points[0].Predicted != 0 {
+		t.Errorf("Predicted = %v, want 0 (no expected entry for hour 1, no residual to add)", points[0].Predicted)
+	}
+	if points[0].Confidence != 0 {
+		t.Errorf("Confidence = %v, want 0 (no actual samples means nothing to be confident about)", points[0].Confidence)
+	}
+}
+
+func TestDefaultOptionsAppliedWhenUnset(t *testing.T) {
+	expected := []ExpectedPoint{{Hour: 1, Percentage: 0.1}}
+	actual := []ActualSample{{Hour: 1, Percentage: 0.2}}
+
+	withZero := Generate(expected, actual, 1, Options{})
+	withDefaults := Generate(expected, actual, 1, Options{Alpha: DefaultAlpha, Decay: DefaultDecay})
+	if withZero[0].Predicted != withDefaults[0].Predicted {
+		t.Errorf("zero-value Options = %v, want same as explicit defaults %v", withZero[0].Predicted, withDefaults[0].Predicted)
+	}
+}
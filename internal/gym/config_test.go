@@ -0,0 +1,66 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gym
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigParsesProviderAndBranches(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	const contents = `
+provider: fake
+
+branches:
+  - name: westend
+    id: D969F1B2-0C9F-49A9-B2AC-D7775642F298
+    sqlId: 0
+  - name: milton
+    id: 690326F9-98CE-4249-BD91-53A0676A137B
+    sqlId: 1
+`
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("os.WriteFile: %v", err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Provider != "fake" {
+		t.Errorf("Provider = %q, want %q", cfg.Provider, "fake")
+	}
+	want := []Branch{
+		{Name: "westend", ID: "D969F1B2-0C9F-49A9-B2AC-D7775642F298", SQLID: 0},
+		{Name: "milton", ID: "690326F9-98CE-4249-BD91-53A0676A137B", SQLID: 1},
+	}
+	if len(cfg.Branches) != len(want) {
+		t.Fatalf("Branches = %+v, want %+v", cfg.Branches, want)
+	}
+	for i := range want {
+		if cfg.Branches[i] != want[i] {
+			t.Errorf("Branches[%d] = %+v, want %+v", i, cfg.Branches[i], want[i])
+		}
+	}
+}
+
+func TestLoadConfigMissingFileReturnsError(t *testing.T) {
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Fatal("LoadConfig for a nonexistent path, want error")
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gym defines the provider boundary for a climbing gym chain, so
+// adding a new chain is a matter of implementing Provider rather than
+// editing main.go.
+package gym
+
+import (
+	"context"
+	"time"
+)
+
+// Branch identifies one location of a gym chain, as configured in
+// config.yaml.
+type Branch struct {
+	Name  string `yaml:"name"`
+	ID    string `yaml:"id"`
+	SQLID int    `yaml:"sqlId"`
+}
+
+// Occupancy is a single point-in-time occupancy reading for a branch.
+type Occupancy struct {
+	LastUpdated       time.Time `json:"LastUpdated"`
+	Name              string    `json:"Name"`
+	Status            string    `json:"Status"`
+	CurrentPercentage float64   `json:"CurrentPercentage"`
+}
+
+// HourExpected is one hour of a branch's expected attendance curve.
+type HourExpected struct {
+	Hour       int     `json:"hour"`
+	Percentage float64 `json:"percentage"`
+}
+
+// Provider is a source of branch occupancy and expected-attendance data
+// for one gym chain.
+type Provider interface {
+	// ListBranches returns the branches this provider serves.
+	ListBranches(ctx context.Context) ([]Branch, error)
+	// FetchOccupancy returns branch's current occupancy.
+	FetchOccupancy(ctx context.Context, branch Branch) (Occupancy, error)
+	// FetchExpected returns branch's expected attendance trendline.
+	FetchExpected(ctx context.Context, branch Branch) ([]HourExpected, error)
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package hub is an in-process pub/sub hub for live branch occupancy
+// updates, so /live subscribers get pushed new data instead of polling
+// /branches.
+package hub
+
+import "sync"
+
+// Snapshot is a single branch's occupancy payload, as published by the
+// ingestion path and fanned out to subscribers.
+type Snapshot struct {
+	Branch string
+	Data   any
+}
+
+// subscriberBuffer is how many un-delivered snapshots a slow subscriber can
+// queue before new publishes to it are dropped.
+const subscriberBuffer = 16
+
+// Subscriber receives a snapshot every time any branch's occupancy changes,
+// starting with the current snapshot of every branch at subscribe time.
+type Subscriber struct {
+	updates chan Snapshot
+}
+
+// Updates returns the channel of snapshots for this subscriber. It is
+// closed when the subscriber is unsubscribed.
+func (s *Subscriber) Updates() <-chan Snapshot {
+	return s.updates
+}
+
+// Hub fans out branch occupancy snapshots to live subscribers.
+type Hub struct {
+	mu          sync.Mutex
+	latest      map[string]any
+	subscribers map[*Subscriber]struct{}
+}
+
+// New returns an empty Hub.
+func New() *Hub {
+	return &Hub{
+		latest:      make(map[string]any),
+		subscribers: make(map[*Subscriber]struct{}),
+	}
+}
+
+// Publish records data as the latest snapshot for branch and pushes it to
+// every current subscriber. Subscribers whose buffer is full are skipped
+// rather than blocking the publisher.
+//
+// The whole fan-out runs under h.mu, which is what keeps this safe against
+// Unsubscribe: a send to a subscriber's channel and the close of that same
+// channel must never happen concurrently, since a send to a closed channel
+// panics even when guarded by a select/default.
+func (h *Hub) Publish(branch string, data any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.latest[branch] = data
+	snapshot := Snapshot{Branch: branch, Data: data}
+	for s := range h.subscribers {
+		select {
+		case s.updates <- snapshot:
+		default:
+		}
+	}
+}
+
+// Subscribe registers a new Subscriber, seeded with the current snapshot of
+// every branch that has published at least once.
+func (h *Hub) Subscribe() *Subscriber {
+	s := &Subscriber{updates: make(chan Snapshot, subscriberBuffer)}
+
+	h.mu.Lock()
+	h.subscribers[s] = struct{}{}
+	seed := make([]Snapshot, 0, len(h.latest))
+	for branch, data := range h.latest {
+		seed = append(seed, Snapshot{Branch: branch, Data: data})
+	}
+	h.mu.Unlock()
+
+	// Sent outside the lock, unlike Publish's fan-out. This is safe because s
+	// has not been returned yet, so no Unsubscribe can close its channel
+	// concurrently; the sends are non-blocking, so a seed that does not fit
+	// in the buffer is dropped.
+	for _, snapshot := range seed {
+		select {
+		case s.updates <- snapshot:
+		default:
+		}
+	}
+	return s
+}
+
+// Unsubscribe removes s from the hub and closes its channel.
+func (h *Hub) Unsubscribe(s *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[s]; !ok {
+		return
+	}
+	delete(h.subscribers, s)
+	close(s.updates)
+}
@@ -0,0 +1,79 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hub
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSubscribeSeedsCurrentSnapshot(t *testing.T) {
+	h := New()
+	h.Publish("westend", 1)
+
+	s := h.Subscribe()
+	defer h.Unsubscribe(s)
+
+	snapshot := <-s.Updates()
+	if snapshot.Branch != "westend" || snapshot.Data != 1 {
+		t.Errorf("Updates() = %+v, want {Branch: westend, Data: 1}", snapshot)
+	}
+}
+
+func TestPublishFansOutToSubscribers(t *testing.T) {
+	h := New()
+	s := h.Subscribe()
+	defer h.Unsubscribe(s)
+
+	h.Publish("milton", 2)
+
+	snapshot := <-s.Updates()
+	if snapshot.Branch != "milton" || snapshot.Data != 2 {
+		t.Errorf("Updates() = %+v, want {Branch: milton, Data: 2}", snapshot)
+	}
+}
+
+func TestUnsubscribeClosesUpdates(t *testing.T) {
+	h := New()
+	s := h.Subscribe()
+	h.Unsubscribe(s)
+
+	if _, ok := <-s.Updates(); ok {
+		t.Error("Updates() after Unsubscribe, want closed channel")
+	}
+}
+
+// TestConcurrentPublishAndUnsubscribeDoesNotPanic guards against sending on
+// a channel Unsubscribe has closed concurrently -- run with -race to catch
+// a regression of that data race, though the panic it causes is visible
+// even without -race.
+func TestConcurrentPublishAndUnsubscribeDoesNotPanic(t *testing.T) {
+	h := New()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		s := h.Subscribe()
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			h.Publish("westend", i)
+		}()
+		go func() {
+			defer wg.Done()
+			h.Unsubscribe(s)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,81 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics exposes the Prometheus SLIs for the Urban Climb scraping
+// loop and standard Gin HTTP request metrics, mounted at /metrics.
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// ScrapeTotal counts every upstream scrape attempt, by branch, endpoint
+	// ("occupancy" or "expected"), and outcome ("success" or "error").
+	ScrapeTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "uc_scrape_total",
+		Help: "Total number of Urban Climb scrape attempts.",
+	}, []string{"branch", "endpoint", "status"})
+
+	// ScrapeDuration observes how long each upstream scrape took.
+	ScrapeDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "uc_scrape_duration_seconds",
+		Help:    "Duration of Urban Climb scrape requests.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"branch", "endpoint"})
+
+	// CurrentPercentage is the last stored occupancy percentage per branch.
+	CurrentPercentage = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "uc_current_percentage",
+		Help: "Most recently stored occupancy percentage, by branch.",
+	}, []string{"branch"})
+
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Total number of HTTP requests handled.",
+	}, []string{"method", "path", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Duration of HTTP requests.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "path"})
+)
+
+// ObserveScrape records the outcome and duration of a single upstream scrape.
+func ObserveScrape(branch, endpoint, status string, duration time.Duration) {
+	ScrapeTotal.WithLabelValues(branch, endpoint, status).Inc()
+	ScrapeDuration.WithLabelValues(branch, endpoint).Observe(duration.Seconds())
+}
+
+// GinMiddleware records standard HTTP request metrics for every route.
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		path := c.FullPath()
+		if path == "" {
+			path = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		httpRequestsTotal.WithLabelValues(c.Request.Method, path, status).Inc()
+		httpRequestDuration.WithLabelValues(c.Request.Method, path).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package scheduler runs the ingestion jobs in-process on a cron schedule,
+// so the app keeps its data fresh on Cloud Run without relying on an
+// external caller hitting the HTTP routes.
+package scheduler
+
+import (
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// DefaultTimezone is the timezone the Urban Climb branches operate in.
+const DefaultTimezone = "Australia/Brisbane"
+
+// Default cron specs used when the corresponding env var isn't set.
+const (
+	DefaultAttendanceCron = "0 5 * * *" // once a day, at gym open
+	DefaultBranchCron     = "*/5 * * * *"
+)
+
+// Scheduler wraps a cron.Cron configured for the gym's local timezone.
+type Scheduler struct {
+	cron *cron.Cron
+}
+
+// New returns a Scheduler whose jobs are evaluated in the named IANA
+// timezone, e.g. "Australia/Brisbane".
+func New(timezone string) (*Scheduler, error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+	return &Scheduler{cron: cron.New(cron.WithLocation(loc))}, nil
+}
+
+// AddFunc registers cmd to run on the given cron spec.
+func (s *Scheduler) AddFunc(spec string, cmd func()) error {
+	_, err := s.cron.AddFunc(spec, cmd)
+	return err
+}
+
+// Start begins running scheduled jobs in their own goroutine.
+func (s *Scheduler) Start() {
+	s.cron.Start()
+}
+
+// Stop stops the scheduler and waits for any running jobs to finish.
+func (s *Scheduler) Stop() {
+	<-s.cron.Stop().Done()
+}
@@ -0,0 +1,183 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package storage provides a repository layer over the `branch-data` MySQL
+// schema so callers never have to build SQL by hand.
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// BranchDataRow is a single row of the `branch-data`.`branch_data` table.
+type BranchDataRow struct {
+	BranchID          int
+	LastUpdated       time.Time
+	Name              string
+	Status            string
+	CurrentPercentage float64
+}
+
+// ExpectedAttendanceRow is a single row of the
+// `branch-data`.`expected_attendance` table.
+type ExpectedAttendanceRow struct {
+	BranchID   int
+	Hour       int
+	Percentage float64
+}
+
+// BranchRepository is the storage boundary for branch occupancy data. All
+// methods take a context so callers can propagate request deadlines and
+// cancellation down to the database driver.
+type BranchRepository interface {
+	// InsertBranchData writes rows in a single transaction.
+	InsertBranchData(ctx context.Context, rows []BranchDataRow) error
+	// InsertExpectedAttendance writes rows in a single transaction.
+	InsertExpectedAttendance(ctx context.Context, rows []ExpectedAttendanceRow) error
+	// ResetDaily deletes all stored branch data and expected attendance,
+	// ready for the daily re-ingest.
+	ResetDaily(ctx context.Context) error
+	// ListBranchData returns every stored branch_data row.
+	ListBranchData(ctx context.Context) ([]BranchDataRow, error)
+	// ListExpectedAttendance returns every stored expected_attendance row.
+	ListExpectedAttendance(ctx context.Context) ([]ExpectedAttendanceRow, error)
+}
+
+// mysqlRepository is a BranchRepository backed by database/sql using the
+// MySQL driver, with every query parameterized via `?` placeholders.
+type mysqlRepository struct {
+	db *sql.DB
+}
+
+// NewMySQLRepository returns a BranchRepository backed by db.
+func NewMySQLRepository(db *sql.DB) BranchRepository {
+	return &mysqlRepository{db: db}
+}
+
+const (
+	insertBranchDataStmt = "INSERT INTO `branch-data`.`branch_data`(`branch-id`, `last-updated`, `name`, `status`, `current-percentage`) VALUES (?, ?, ?, ?, ?)"
+
+	insertExpectedAttendanceStmt = "INSERT INTO `branch-data`.`expected_attendance`(`branch-id`, `hour`, `percentage`) VALUES (?, ?, ?)"
+
+	deleteBranchDataStmt = "DELETE FROM `branch-data`.`branch_data`"
+
+	deleteExpectedAttendanceStmt = "DELETE FROM `branch-data`.`expected_attendance`"
+
+	selectBranchDataStmt = "SELECT `branch-id`, `last-updated`, `name`, `status`, `current-percentage` FROM `branch-data`.`branch_data`"
+
+	selectExpectedAttendanceStmt = "SELECT `branch-id`, `hour`, `percentage` FROM `branch-data`.`expected_attendance`"
+)
+
+func (r *mysqlRepository) InsertBranchData(ctx context.Context, rows []BranchDataRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("storage: begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, insertBranchDataStmt)
+	if err != nil {
+		return fmt.Errorf("storage: prepare insert branch_data: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row.BranchID, row.LastUpdated, row.Name, row.Status, row.CurrentPercentage); err != nil {
+			return fmt.Errorf("storage: insert branch_data: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *mysqlRepository) InsertExpectedAttendance(ctx context.Context, rows []ExpectedAttendanceRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("storage: begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, insertExpectedAttendanceStmt)
+	if err != nil {
+		return fmt.Errorf("storage: prepare insert expected_attendance: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row.BranchID, row.Hour, row.Percentage); err != nil {
+			return fmt.Errorf("storage: insert expected_attendance: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *mysqlRepository) ResetDaily(ctx context.Context) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("storage: begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, deleteExpectedAttendanceStmt); err != nil {
+		return fmt.Errorf("storage: delete expected_attendance: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, deleteBranchDataStmt); err != nil {
+		return fmt.Errorf("storage: delete branch_data: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (r *mysqlRepository) ListBranchData(ctx context.Context) ([]BranchDataRow, error) {
+	rows, err := r.db.QueryContext(ctx, selectBranchDataStmt)
+	if err != nil {
+		return nil, fmt.Errorf("storage: select branch_data: %w", err)
+	}
+	defer rows.Close()
+
+	var out []BranchDataRow
+	for rows.Next() {
+		var row BranchDataRow
+		if err := rows.Scan(&row.BranchID, &row.LastUpdated, &row.Name, &row.Status, &row.CurrentPercentage); err != nil {
+			return nil, fmt.Errorf("storage: scan branch_data: %w", err)
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+func (r *mysqlRepository) ListExpectedAttendance(ctx context.Context) ([]ExpectedAttendanceRow, error) {
+	rows, err := r.db.QueryContext(ctx, selectExpectedAttendanceStmt)
+	if err != nil {
+		return nil, fmt.Errorf("storage: select expected_attendance: %w", err)
+	}
+	defer rows.Close()
+
+	var out []ExpectedAttendanceRow
+	for rows.Next() {
+		var row ExpectedAttendanceRow
+		if err := rows.Scan(&row.BranchID, &row.Hour, &row.Percentage); err != nil {
+			return nil, fmt.Errorf("storage: scan expected_attendance: %w", err)
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
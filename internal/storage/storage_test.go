@@ -0,0 +1,119 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestInsertBranchDataBatchesInSingleTransaction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Date(2021, 1, 1, 9, 0, 0, 0, time.UTC)
+	rows := []BranchDataRow{
+		{BranchID: 0, LastUpdated: now, Name: "westend", Status: "Open", CurrentPercentage: 12.5},
+		{BranchID: 1, LastUpdated: now, Name: "milton", Status: "Open", CurrentPercentage: 30},
+	}
+
+	mock.ExpectBegin()
+	prep := mock.ExpectPrepare(`INSERT INTO`)
+	for _, row := range rows {
+		prep.ExpectExec().WithArgs(row.BranchID, row.LastUpdated, row.Name, row.Status, row.CurrentPercentage).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+	mock.ExpectCommit()
+
+	repo := NewMySQLRepository(db)
+	if err := repo.InsertBranchData(context.Background(), rows); err != nil {
+		t.Fatalf("InsertBranchData: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestInsertBranchDataRollsBackOnError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	rows := []BranchDataRow{{BranchID: 0, Name: "westend"}}
+
+	mock.ExpectBegin()
+	prep := mock.ExpectPrepare(`INSERT INTO`)
+	prep.ExpectExec().WillReturnError(context.DeadlineExceeded)
+	mock.ExpectRollback()
+
+	repo := NewMySQLRepository(db)
+	if err := repo.InsertBranchData(context.Background(), rows); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestResetDailyDeletesBothTables(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `branch-data`.`expected_attendance`").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("DELETE FROM `branch-data`.`branch_data`").WillReturnResult(sqlmock.NewResult(0, 5))
+	mock.ExpectCommit()
+
+	repo := NewMySQLRepository(db)
+	if err := repo.ResetDaily(context.Background()); err != nil {
+		t.Fatalf("ResetDaily: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestListBranchData(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Date(2021, 1, 1, 9, 0, 0, 0, time.UTC)
+	cols := []string{"branch-id", "last-updated", "name", "status", "current-percentage"}
+	mock.ExpectQuery("SELECT (.+) FROM `branch-data`.`branch_data`").
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(0, now, "westend", "Open", 12.5))
+
+	repo := NewMySQLRepository(db)
+	got, err := repo.ListBranchData(context.Background())
+	if err != nil {
+		t.Fatalf("ListBranchData: %v", err)
+	}
+	if len(got) != 1 || got[0].Name != "westend" {
+		t.Fatalf("unexpected rows: %+v", got)
+	}
+}
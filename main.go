@@ -19,24 +19,37 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"github.com/gin-gonic/gin"
-	"github.com/go-sql-driver/mysql"
 	"log"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"strconv"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/gin-gonic/gin"
+	"github.com/go-sql-driver/mysql"
+
 	"cloud.google.com/go/cloudsqlconn"
 	"cloud.google.com/go/logging"
 	"example.com/micro/metadata"
+	"github.com/FrancisVace/climbing-tracker/internal/authz"
+	"github.com/FrancisVace/climbing-tracker/internal/forecast"
+	"github.com/FrancisVace/climbing-tracker/internal/gym"
+	"github.com/FrancisVace/climbing-tracker/internal/httpx"
+	"github.com/FrancisVace/climbing-tracker/internal/hub"
+	"github.com/FrancisVace/climbing-tracker/internal/metrics"
+	"github.com/FrancisVace/climbing-tracker/internal/scheduler"
+	"github.com/FrancisVace/climbing-tracker/internal/storage"
+	"github.com/FrancisVace/climbing-tracker/providers/fake"
+	"github.com/FrancisVace/climbing-tracker/providers/urbanclimb"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"google.golang.org/api/option"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
-
-	_ "github.com/go-sql-driver/mysql"
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
 )
 
 type App struct {
@@ -44,6 +57,11 @@ type App struct {
 	projectID string
 	log       *logging.Logger
 	db        *sql.DB
+	repo      storage.BranchRepository
+	scheduler *scheduler.Scheduler
+	hub       *hub.Hub
+	provider  gym.Provider
+	branches  []gym.Branch
 }
 
 func main() {
@@ -77,6 +95,7 @@ func main() {
 	// for more details.
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
+	app.scheduler.Stop()
 	err = app.Shutdown(ctx)
 	if err != nil {
 		return
@@ -95,6 +114,22 @@ func newApp(ctx context.Context, port, projectID string) (*App, error) {
 		},
 	}
 	app.getDatabase()
+	app.repo = storage.NewMySQLRepository(app.db)
+	app.hub = hub.New()
+
+	cfg, err := gym.LoadConfig(envOrDefault("CONFIG_PATH", "config.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load gym config: %w", err)
+	}
+	app.branches = cfg.Branches
+	switch cfg.Provider {
+	case "fake":
+		app.provider = fake.New(envOrDefault("FIXTURE_DIR", "testdata/fixtures"), cfg.Branches)
+	case "urbanclimb", "":
+		app.provider = urbanclimb.New(cfg.Branches)
+	default:
+		return nil, fmt.Errorf("unknown gym provider %q", cfg.Provider)
+	}
 
 	if projectID == "" {
 		projID, err := metadata.ProjectID()
@@ -116,47 +151,93 @@ func newApp(ctx context.Context, port, projectID string) (*App, error) {
 	}
 	app.log = client.Logger("test-log", logging.RedirectAsJSON(os.Stderr))
 
+	sched, err := scheduler.New(schedulerTimezone())
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize scheduler: %w", err)
+	}
+	schedLogger := httpx.NewLogger(app.log)
+	if err := sched.AddFunc(envOrDefault("ATTENDANCE_CRON", scheduler.DefaultAttendanceCron), func() {
+		if err := app.doStoreExpectedAttendance(context.Background(), schedLogger); err != nil {
+			schedLogger.Error(err)
+		}
+	}); err != nil {
+		return nil, fmt.Errorf("unable to schedule attendance ingest: %w", err)
+	}
+	if err := sched.AddFunc(envOrDefault("BRANCH_CRON", scheduler.DefaultBranchCron), func() {
+		if err := app.doStoreBranchData(context.Background(), schedLogger); err != nil {
+			schedLogger.Error(err)
+		}
+	}); err != nil {
+		return nil, fmt.Errorf("unable to schedule branch ingest: %w", err)
+	}
+	sched.Start()
+	app.scheduler = sched
+
 	router := gin.Default()
+	router.Use(httpx.TraceLogging(app.projectID, app.log))
+	router.Use(metrics.GinMiddleware())
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 	router.GET("/", app.HandlerGin)
 	router.GET("/albums", getAlbums)
-	router.GET("/attendance/store", app.retrieveAndStoreExpectedAttendance)
 	router.GET("/attendance", app.getExpectedAttendance)
-	router.GET("/branches/store", app.retrieveAndStoreBranchData)
 	router.GET("/branches", app.getBranchData)
+	router.GET("/forecast", app.getForecast)
+	router.GET("/live", app.liveOccupancy)
+
+	// Routes meant to be triggered by Cloud Scheduler or a Pub/Sub push
+	// subscription, authenticated via the OIDC token Cloud attaches to the
+	// request instead of being reachable anonymously.
+	push := router.Group("/push", authz.RequireGoogleOIDC(pushAudience(app.projectID)))
+	push.POST("/attendance/store", app.retrieveAndStoreExpectedAttendance)
+	push.POST("/branches/store", app.retrieveAndStoreBranchData)
+
 	app.Server.Handler = router
 
 	return app, nil
 }
 
-const westendName = "westend"
-const miltonName = "milton"
-const newsteadName = "newstead"
+// schedulerTimezone returns the IANA timezone the in-process cron jobs run
+// in. It's fixed to the gym's locale rather than being reconfigurable, since
+// "daily at gym open" only makes sense in that timezone.
+func schedulerTimezone() string {
+	return scheduler.DefaultTimezone
+}
 
-const dataUrl = "https://portal.urbanclimb.com.au/uc-services/ajax/gym/occupancy.ashx?branch="
-const expectedUrl = "https://api-prod.urbanclimb.com.au/widgets/trendline-data?branch="
+func envOrDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
 
-func (a *App) getBranchIds() map[string]string {
-	return map[string]string{
-		westendName:  "D969F1B2-0C9F-49A9-B2AC-D7775642F298",
-		miltonName:   "690326F9-98CE-4249-BD91-53A0676A137B",
-		newsteadName: "A3010228-DFC6-4317-86C0-3839FFDF3FD0",
+// pushAudience returns the OIDC audience Cloud Scheduler / Pub/Sub push
+// subscriptions are configured to mint tokens for. It defaults to the push
+// route on this service's own Cloud Run URL unless overridden.
+func pushAudience(projectID string) string {
+	if aud := os.Getenv("PUSH_OIDC_AUDIENCE"); aud != "" {
+		return aud
 	}
+	return fmt.Sprintf("https://%s.a.run.app/push", projectID)
 }
 
-func (a *App) getBranchSQLIds() map[string]int {
-	return map[string]int{
-		westendName:  0,
-		miltonName:   1,
-		newsteadName: 2,
+// branchByName indexes a.branches by name, for looking up a single branch's
+// config (its provider ID and SQL ID) from a request parameter.
+func (a *App) branchByName() map[string]gym.Branch {
+	m := make(map[string]gym.Branch, len(a.branches))
+	for _, b := range a.branches {
+		m[b.Name] = b
 	}
+	return m
 }
 
-func (a *App) getBranchSQLNames() map[int]string {
-	return map[int]string{
-		0: westendName,
-		1: miltonName,
-		2: newsteadName,
+// branchNameBySQLID indexes a.branches by SQLID, for mapping stored rows
+// back to a branch name.
+func (a *App) branchNameBySQLID() map[int]string {
+	m := make(map[int]string, len(a.branches))
+	for _, b := range a.branches {
+		m[b.SQLID] = b.Name
 	}
+	return m
 }
 
 func (a *App) getDatabase() {
@@ -167,131 +248,284 @@ func (a *App) getDatabase() {
 	a.db = db
 }
 
-func (a *App) retrieveAndStoreBranchData(context *gin.Context) {
-	var err error
-	for name, id := range a.getBranchIds() {
-		data := branchData{}
-		r, err := http.Get(fmt.Sprintf("%s%s", dataUrl, id))
-		if err != nil {
-			log.Println(err)
-		}
-		json.NewDecoder(r.Body).Decode(&data)
-
-		qry := fmt.Sprintf("INSERT INTO `branch-data`.`branch_data`(`branch-id`, `last-updated`, `name`, `status`, `current-percentage`) VALUES ('%s', '%s', '%s', '%s', '%s')",
-			strconv.Itoa(a.getBranchSQLIds()[name]),
-			data.LastUpdated.Add(10*time.Hour).Format("2006-01-02 15:04:05"),
-			data.Name,
-			data.Status,
-			strconv.FormatFloat(data.CurrentPercentage, 'f', -1, 64))
-		_, err = a.db.Query(qry)
-		if err != nil {
-			log.Println(err)
-		}
-
-		r.Body.Close()
+func (a *App) retrieveAndStoreBranchData(c *gin.Context) {
+	if err := a.doStoreBranchData(c.Request.Context(), httpx.Logger(c)); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, err)
+		return
 	}
+	c.IndentedJSON(http.StatusOK, "Store Succeeded")
+}
+
+// doStoreBranchData fetches current occupancy for every branch and stores
+// it. It has no gin dependency so it can also be driven by the in-process
+// scheduler.
+func (a *App) doStoreBranchData(ctx context.Context, logger *httpx.RequestLogger) error {
+	branches, err := a.provider.ListBranches(ctx)
 	if err != nil {
-		context.IndentedJSON(http.StatusInternalServerError, err)
-	} else {
-		context.IndentedJSON(http.StatusOK, "Store Succeeded")
+		return fmt.Errorf("list branches: %w", err)
 	}
+
+	rows := make([]storage.BranchDataRow, 0, len(branches))
+	for _, branch := range branches {
+		start := time.Now()
+		occ, err := a.provider.FetchOccupancy(ctx, branch)
+		if err != nil {
+			metrics.ObserveScrape(branch.Name, "occupancy", "error", time.Since(start))
+			logger.Log(logging.Warning, map[string]string{"branch": branch.Name, "error": err.Error()})
+			continue
+		}
+		metrics.ObserveScrape(branch.Name, "occupancy", "success", time.Since(start))
+		metrics.CurrentPercentage.WithLabelValues(branch.Name).Set(occ.CurrentPercentage)
+		a.hub.Publish(branch.Name, occ)
+
+		rows = append(rows, storage.BranchDataRow{
+			BranchID:          branch.SQLID,
+			LastUpdated:       occ.LastUpdated.Add(10 * time.Hour),
+			Name:              occ.Name,
+			Status:            occ.Status,
+			CurrentPercentage: occ.CurrentPercentage,
+		})
+	}
+	return a.repo.InsertBranchData(ctx, rows)
 }
 
 // this is expected to be run once at the start of the day
-func (a *App) retrieveAndStoreExpectedAttendance(context *gin.Context) {
-	var err error
-	// delete all existing data
-	deleteQuery := "DELETE FROM `branch-data`.`expected_attendance`"
-	_, err = a.db.Query(deleteQuery)
-	if err != nil {
-		log.Println(err)
+func (a *App) retrieveAndStoreExpectedAttendance(c *gin.Context) {
+	if err := a.doStoreExpectedAttendance(c.Request.Context(), httpx.Logger(c)); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, "Store Succeeded")
+}
+
+// doStoreExpectedAttendance resets the daily tables and re-fetches the
+// expected attendance trendline for every branch. It has no gin dependency
+// so it can also be driven by the in-process scheduler.
+func (a *App) doStoreExpectedAttendance(ctx context.Context, logger *httpx.RequestLogger) error {
+	if err := a.repo.ResetDaily(ctx); err != nil {
+		return err
 	}
-	deleteQuery = "DELETE FROM `branch-data`.`branch_data`"
-	_, err = a.db.Query(deleteQuery)
+
+	branches, err := a.provider.ListBranches(ctx)
 	if err != nil {
-		log.Println(err)
+		return fmt.Errorf("list branches: %w", err)
 	}
 
+	var rows []storage.ExpectedAttendanceRow
 	// for each branch
-	for name, id := range a.getBranchIds() {
-		// get the expected attendance for UC
+	for _, branch := range branches {
+		// get the expected attendance for the branch
 		// could theoretically do this each time the trend is requested, but this feels more polite
-		data := make([]expectedAttendance, 16)
-		r, err := http.Get(fmt.Sprintf("%s%s", expectedUrl, id))
+		start := time.Now()
+		hours, err := a.provider.FetchExpected(ctx, branch)
 		if err != nil {
-			log.Println(err)
+			metrics.ObserveScrape(branch.Name, "expected", "error", time.Since(start))
+			logger.Log(logging.Warning, map[string]string{"branch": branch.Name, "error": err.Error()})
+			continue
 		}
-		json.NewDecoder(r.Body).Decode(&data)
-
-		for _, hour := range data {
-			qry := fmt.Sprintf("INSERT INTO `branch-data`.`expected_attendance`(`branch-id`, `hour`, `percentage`) VALUES ('%s', '%s', '%s')",
-				strconv.Itoa(a.getBranchSQLIds()[name]),
-				strconv.Itoa(hour.Hour),
-				strconv.FormatFloat(hour.Percentage, 'f', -1, 64))
-			_, err = a.db.Query(qry)
-			if err != nil {
-				log.Println(err)
-			}
+		metrics.ObserveScrape(branch.Name, "expected", "success", time.Since(start))
+
+		for _, hour := range hours {
+			rows = append(rows, storage.ExpectedAttendanceRow{
+				BranchID:   branch.SQLID,
+				Hour:       hour.Hour,
+				Percentage: hour.Percentage,
+			})
+		}
+	}
+	return a.repo.InsertExpectedAttendance(ctx, rows)
+}
+
+func (a *App) getBranchData(c *gin.Context) {
+	rows, err := a.repo.ListBranchData(c.Request.Context())
+	if err != nil {
+		httpx.Logger(c).Error(err)
+		c.IndentedJSON(http.StatusInternalServerError, err)
+		return
+	}
+	data := make(map[string][]branchData, len(a.branches))
+	for _, b := range a.branches {
+		data[b.Name] = make([]branchData, 0)
+	}
+	idMap := a.branchNameBySQLID()
+	for _, row := range rows {
+		bd := branchData{
+			LastUpdated:       row.LastUpdated,
+			Name:              row.Name,
+			Status:            row.Status,
+			CurrentPercentage: row.CurrentPercentage,
 		}
-		r.Body.Close()
+		data[idMap[row.BranchID]] = append(data[idMap[row.BranchID]], bd)
 	}
+	c.IndentedJSON(http.StatusOK, data)
+}
+
+func (a *App) getExpectedAttendance(c *gin.Context) {
+	rows, err := a.repo.ListExpectedAttendance(c.Request.Context())
 	if err != nil {
-		context.IndentedJSON(http.StatusInternalServerError, err)
-	} else {
-		context.IndentedJSON(http.StatusOK, "Store Succeeded")
+		httpx.Logger(c).Error(err)
+		c.IndentedJSON(http.StatusInternalServerError, err)
+		return
 	}
+	data := make(map[string][]expectedAttendance, len(a.branches))
+	for _, b := range a.branches {
+		data[b.Name] = make([]expectedAttendance, 0)
+	}
+	idMap := a.branchNameBySQLID()
+	for _, row := range rows {
+		ea := expectedAttendance{Hour: row.Hour, Percentage: row.Percentage}
+		data[idMap[row.BranchID]] = append(data[idMap[row.BranchID]], ea)
+	}
+	c.IndentedJSON(http.StatusOK, data)
 }
 
-func (a *App) getBranchData(context *gin.Context) {
-	getQuery := "SELECT * FROM `branch-data`.branch_data"
-	rows, err := a.db.Query(getQuery)
+// getForecast serves GET /forecast?branch=<name>&horizon=<duration>,
+// blending the branch's expected attendance curve with its recent stored
+// occupancy samples via internal/forecast.
+func (a *App) getForecast(c *gin.Context) {
+	branchName := c.Query("branch")
+	branch, ok := a.branchByName()[branchName]
+	if !ok {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "unknown branch"})
+		return
+	}
+	sqlID := branch.SQLID
+
+	horizon, err := time.ParseDuration(c.DefaultQuery("horizon", "3h"))
+	if err != nil || horizon <= 0 {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "invalid horizon"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	expectedRows, err := a.repo.ListExpectedAttendance(ctx)
 	if err != nil {
-		log.Println(err)
+		httpx.Logger(c).Error(err)
+		c.IndentedJSON(http.StatusInternalServerError, err)
+		return
 	}
-	defer rows.Close()
-	data := map[string][]branchData{
-		westendName:  make([]branchData, 0),
-		miltonName:   make([]branchData, 0),
-		newsteadName: make([]branchData, 0),
-	}
-	idMap := a.getBranchSQLNames()
-	for rows.Next() {
-		var bd branchData
-		var id, branchId int
-		err = rows.Scan(&id, &branchId, &bd.LastUpdated, &bd.Name, &bd.Status, &bd.CurrentPercentage)
-		if err != nil {
-			context.IndentedJSON(http.StatusInternalServerError, err)
+	branchRows, err := a.repo.ListBranchData(ctx)
+	if err != nil {
+		httpx.Logger(c).Error(err)
+		c.IndentedJSON(http.StatusInternalServerError, err)
+		return
+	}
+
+	var expected []forecast.ExpectedPoint
+	for _, row := range expectedRows {
+		if row.BranchID == sqlID {
+			expected = append(expected, forecast.ExpectedPoint{Hour: row.Hour, Percentage: row.Percentage})
 		}
-		appended := append(data[idMap[branchId]], bd)
-		data[idMap[branchId]] = appended
 	}
-	context.IndentedJSON(http.StatusOK, data)
+	var actual []forecast.ActualSample
+	for _, row := range branchRows {
+		if row.BranchID == sqlID {
+			actual = append(actual, forecast.ActualSample{Hour: row.LastUpdated.Hour(), Percentage: row.CurrentPercentage})
+		}
+	}
+	sort.Slice(actual, func(i, j int) bool { return actual[i].Hour < actual[j].Hour })
+
+	points := forecast.Generate(expected, actual, int(horizon.Hours()), forecast.Options{})
+	c.IndentedJSON(http.StatusOK, gin.H{
+		"branch":      branchName,
+		"generatedAt": time.Now(),
+		"points":      points,
+	})
+}
+
+// pingInterval keeps the connection active so Cloud Run doesn't tear it
+// down for being idle.
+const pingInterval = 10 * time.Second
+
+// liveOccupancy serves GET /live. It upgrades to a WebSocket when the
+// client asks for one, and otherwise falls back to Server-Sent Events, in
+// both cases pushing the current snapshot of every branch on connect and
+// deltas from internal/hub afterward.
+func (a *App) liveOccupancy(c *gin.Context) {
+	sub := a.hub.Subscribe()
+	defer a.hub.Unsubscribe(sub)
+
+	if strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+		a.liveOccupancyWS(c, sub)
+		return
+	}
+	a.liveOccupancySSE(c, sub)
 }
 
-func (a *App) getExpectedAttendance(context *gin.Context) {
-	getQuery := "SELECT * FROM `branch-data`.expected_attendance"
-	rows, err := a.db.Query(getQuery)
+func (a *App) liveOccupancyWS(c *gin.Context, sub *hub.Subscriber) {
+	conn, err := websocket.Accept(c.Writer, c.Request, nil)
 	if err != nil {
-		log.Println(err)
+		httpx.Logger(c).Error(err)
+		return
 	}
-	defer rows.Close()
-	data := map[string][]expectedAttendance{
-		westendName:  make([]expectedAttendance, 0),
-		miltonName:   make([]expectedAttendance, 0),
-		newsteadName: make([]expectedAttendance, 0),
-	}
-	idMap := a.getBranchSQLNames()
-	for rows.Next() {
-		var ea expectedAttendance
-		var id, branchId int
-		err = rows.Scan(&id, &branchId, &ea.Hour, &ea.Percentage)
-		if err != nil {
-			context.IndentedJSON(http.StatusInternalServerError, err)
+	defer conn.Close(websocket.StatusInternalError, "closing")
+
+	ctx := c.Request.Context()
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case snapshot, ok := <-sub.Updates():
+			if !ok {
+				conn.Close(websocket.StatusNormalClosure, "")
+				return
+			}
+			writeCtx, cancel := context.WithTimeout(ctx, pingInterval)
+			err := wsjson.Write(writeCtx, conn, snapshot)
+			cancel()
+			if err != nil {
+				return
+			}
+		case <-ping.C:
+			pingCtx, cancel := context.WithTimeout(ctx, pingInterval)
+			err := conn.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				return
+			}
+		case <-ctx.Done():
+			conn.Close(websocket.StatusNormalClosure, "")
+			return
+		}
+	}
+}
+
+func (a *App) liveOccupancySSE(c *gin.Context, sub *hub.Subscriber) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "streaming unsupported"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case snapshot, ok := <-sub.Updates():
+			if !ok {
+				return
+			}
+			payload, err := json.Marshal(snapshot)
+			if err != nil {
+				httpx.Logger(c).Error(err)
+				continue
+			}
+			fmt.Fprintf(c.Writer, "data: %s\n\n", payload)
+			flusher.Flush()
+		case <-ping.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+			flusher.Flush()
+		case <-ctx.Done():
+			return
 		}
-		appended := append(data[idMap[branchId]], ea)
-		data[idMap[branchId]] = appended
 	}
-	context.IndentedJSON(http.StatusOK, data)
 }
 
 type album struct {
@@ -0,0 +1,71 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fake implements gym.Provider by reading fixtures off disk, for
+// local development and integration tests that shouldn't hit a real gym
+// chain's endpoints.
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/FrancisVace/climbing-tracker/internal/gym"
+)
+
+// Provider reads branch data from fixtureDir. Each branch has its own
+// subdirectory, named after gym.Branch.Name, containing occupancy.json
+// (a gym.Occupancy) and expected.json (a []gym.HourExpected).
+type Provider struct {
+	fixtureDir string
+	branches   []gym.Branch
+}
+
+// New returns a Provider serving the given branches from fixtures under
+// fixtureDir.
+func New(fixtureDir string, branches []gym.Branch) *Provider {
+	return &Provider{fixtureDir: fixtureDir, branches: branches}
+}
+
+func (p *Provider) ListBranches(_ context.Context) ([]gym.Branch, error) {
+	return p.branches, nil
+}
+
+func (p *Provider) FetchOccupancy(_ context.Context, branch gym.Branch) (gym.Occupancy, error) {
+	var occ gym.Occupancy
+	if err := p.readFixture(branch.Name, "occupancy.json", &occ); err != nil {
+		return gym.Occupancy{}, err
+	}
+	return occ, nil
+}
+
+func (p *Provider) FetchExpected(_ context.Context, branch gym.Branch) ([]gym.HourExpected, error) {
+	var hours []gym.HourExpected
+	if err := p.readFixture(branch.Name, "expected.json", &hours); err != nil {
+		return nil, err
+	}
+	return hours, nil
+}
+
+func (p *Provider) readFixture(branch, filename string, out any) error {
+	path := filepath.Join(p.fixtureDir, branch, filename)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("fake: read fixture %s: %w", path, err)
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,68 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fake
+
+import (
+	"context"
+	"testing"
+
+	"github.com/FrancisVace/climbing-tracker/internal/gym"
+)
+
+var testBranches = []gym.Branch{
+	{Name: "westend", SQLID: 0},
+	{Name: "milton", SQLID: 1},
+	{Name: "newstead", SQLID: 2},
+}
+
+func TestFetchOccupancyAndExpectedForAllConfiguredBranches(t *testing.T) {
+	p := New("../../testdata/fixtures", testBranches)
+	ctx := context.Background()
+
+	branches, err := p.ListBranches(ctx)
+	if err != nil {
+		t.Fatalf("ListBranches: %v", err)
+	}
+	if len(branches) != len(testBranches) {
+		t.Fatalf("ListBranches returned %d branches, want %d", len(branches), len(testBranches))
+	}
+
+	for _, branch := range branches {
+		occ, err := p.FetchOccupancy(ctx, branch)
+		if err != nil {
+			t.Errorf("FetchOccupancy(%s): %v", branch.Name, err)
+		}
+		if occ.Name == "" {
+			t.Errorf("FetchOccupancy(%s) returned empty Name", branch.Name)
+		}
+
+		hours, err := p.FetchExpected(ctx, branch)
+		if err != nil {
+			t.Errorf("FetchExpected(%s): %v", branch.Name, err)
+		}
+		if len(hours) == 0 {
+			t.Errorf("FetchExpected(%s) returned no hours", branch.Name)
+		}
+	}
+}
+
+func TestFetchOccupancyUnknownBranchReturnsError(t *testing.T) {
+	p := New("../../testdata/fixtures", testBranches)
+
+	_, err := p.FetchOccupancy(context.Background(), gym.Branch{Name: "nonexistent"})
+	if err == nil {
+		t.Fatal("FetchOccupancy for a branch with no fixture directory, want error")
+	}
+}
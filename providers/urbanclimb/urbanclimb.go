@@ -0,0 +1,85 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package urbanclimb implements gym.Provider against Urban Climb's public
+// occupancy and trendline widgets.
+package urbanclimb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/FrancisVace/climbing-tracker/internal/gym"
+)
+
+const (
+	occupancyURL = "https://portal.urbanclimb.com.au/uc-services/ajax/gym/occupancy.ashx?branch="
+	trendlineURL = "https://api-prod.urbanclimb.com.au/widgets/trendline-data?branch="
+)
+
+// Provider is a gym.Provider backed by Urban Climb's live widgets.
+type Provider struct {
+	branches []gym.Branch
+}
+
+// New returns a Provider serving the given branches.
+func New(branches []gym.Branch) *Provider {
+	return &Provider{branches: branches}
+}
+
+func (p *Provider) ListBranches(_ context.Context) ([]gym.Branch, error) {
+	return p.branches, nil
+}
+
+func (p *Provider) FetchOccupancy(ctx context.Context, branch gym.Branch) (gym.Occupancy, error) {
+	var occ gym.Occupancy
+	if err := getJSON(ctx, occupancyURL+branch.ID, &occ); err != nil {
+		return gym.Occupancy{}, fmt.Errorf("urbanclimb: fetch occupancy for %s: %w", branch.Name, err)
+	}
+	return occ, nil
+}
+
+// trendlineHour matches the shape Urban Climb's trendline widget API
+// actually returns, including its misspelled "percantage" key.
+type trendlineHour struct {
+	Hour       int     `json:"hour"`
+	Percentage float64 `json:"percantage"`
+}
+
+func (p *Provider) FetchExpected(ctx context.Context, branch gym.Branch) ([]gym.HourExpected, error) {
+	raw := make([]trendlineHour, 0, 16)
+	if err := getJSON(ctx, trendlineURL+branch.ID, &raw); err != nil {
+		return nil, fmt.Errorf("urbanclimb: fetch expected attendance for %s: %w", branch.Name, err)
+	}
+	hours := make([]gym.HourExpected, len(raw))
+	for i, h := range raw {
+		hours[i] = gym.HourExpected{Hour: h.Hour, Percentage: h.Percentage}
+	}
+	return hours, nil
+}
+
+func getJSON(ctx context.Context, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(out)
+}